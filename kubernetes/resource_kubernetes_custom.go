@@ -1,21 +1,54 @@
 package kubernetes
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cenkalti/backoff"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	yamlutil "k8s.io/apimachinery/pkg/util/yaml"
+
+	"k8s.io/apimachinery/pkg/util/jsonmergepatch"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"k8s.io/client-go/discovery"
+	diskcached "k8s.io/client-go/discovery/cached/disk"
+	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 )
 
+// defaultFieldManager is the field manager used for Server-Side Apply
+// requests when the user does not set `field_manager` explicitly.
+const defaultFieldManager = "terraform-provider-kubernetes"
+
+// lastAppliedConfigAnnotation records the previously applied configuration
+// on the live object, the same annotation `kubectl apply` uses, so the
+// three-way merge fallback below can recover the "original" even across
+// provider restarts.
+const lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
 func resourceKubernetesCustom() *schema.Resource {
 	return &schema.Resource{
 		Create: resourceKubernetesCustomCreate,
@@ -28,19 +61,31 @@ func resourceKubernetesCustom() *schema.Resource {
 		},
 		Timeouts: &schema.ResourceTimeout{
 			Create: schema.DefaultTimeout(5 * time.Minute),
+			Update: schema.DefaultTimeout(5 * time.Minute),
 			Delete: schema.DefaultTimeout(5 * time.Minute),
 		},
 
 		Schema: map[string]*schema.Schema{
 			"json": {
 				Type:        schema.TypeString,
-				Description: "",
+				Description: "A JSON document, a single YAML document, or a multi-document YAML manifest stream (documents separated by `---`) describing one or more Kubernetes objects",
 				Required:    true,
 
 				DiffSuppressFunc: func(k, oldJSON, newJSON string, d *schema.ResourceData) bool {
 					// FIXME handle errors
-					old, _ := parseKubernetesConfig(oldJSON)
-					new, _ := parseKubernetesConfig(newJSON)
+					old, errOld := parseKubernetesConfig(oldJSON)
+					new, errNew := parseKubernetesConfig(newJSON)
+
+					if errOld != nil || errNew != nil {
+						return false
+					}
+
+					applyFieldRules(old, d)
+					applyFieldRules(new, d)
+					mergeComputedFields(old, new, d)
+
+					sortManifestsForApply(old)
+					sortManifestsForApply(new)
 
 					if reflect.DeepEqual(old, new) {
 						return true
@@ -49,74 +94,450 @@ func resourceKubernetesCustom() *schema.Resource {
 					return false
 				},
 			},
+			"objects": {
+				Type:        schema.TypeList,
+				Description: "The set of objects managed by this resource, one entry per manifest in `json`",
+				Computed:    true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"api_version": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"kind": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"namespace": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"name": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"id": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+					},
+				},
+			},
+			"force_conflicts": {
+				Type:        schema.TypeBool,
+				Description: "Force ownership of fields that are contested by another field manager when applying this resource",
+				Optional:    true,
+				Default:     false,
+			},
+			"field_manager": {
+				Type:        schema.TypeString,
+				Description: "Set the name of the field manager used for Server-Side Apply",
+				Optional:    true,
+				Default:     defaultFieldManager,
+			},
+			"ignore_fields": {
+				Type:        schema.TypeList,
+				Description: "Dotted field paths (e.g. `spec.replicas`) to strip from the diff, in addition to this provider's built-in defaults for the object's kind",
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"computed_fields": {
+				Type:        schema.TypeList,
+				Description: "Dotted field paths (e.g. `spec.clusterIP`) that are expected to be set by the server; their live value is merged into the desired state before diffing so they don't show up as drift",
+				Optional:    true,
+				Elem:        &schema.Schema{Type: schema.TypeString},
+			},
+			"wait_for": {
+				Type:        schema.TypeList,
+				Description: "Block Create/Update until the resource reaches a desired state",
+				Optional:    true,
+				MaxItems:    1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"condition": {
+							Type:        schema.TypeList,
+							Description: "Wait for a condition in `status.conditions` to reach the given status",
+							Optional:    true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"type": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"status": {
+										Type:     schema.TypeString,
+										Optional: true,
+										Default:  "True",
+									},
+								},
+							},
+						},
+						"field": {
+							Type:        schema.TypeList,
+							Description: "Wait for a dotted field path to equal the given value",
+							Optional:    true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"key": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"value": {
+										Type:     schema.TypeString,
+										Required: true,
+									},
+								},
+							},
+						},
+						"rollout": {
+							Type:        schema.TypeBool,
+							Description: "Wait for a Deployment/StatefulSet/DaemonSet rollout to finish",
+							Optional:    true,
+							Default:     false,
+						},
+					},
+				},
+			},
 		},
 	}
 }
 
 func resourceKubernetesCustomCreate(d *schema.ResourceData, m interface{}) error {
 	config := d.Get("json").(string)
-	unstructuredResource, _ := parseKubernetesConfig(config)
+	manifests, err := parseKubernetesConfig(config)
+
+	if err != nil {
+		return fmt.Errorf("Could not parse resource: %v", err)
+	}
+
+	sortManifestsForApply(manifests)
+
+	// The ID is derived from the desired manifest set, not from what
+	// actually got applied, and is set before doing any work so that a
+	// Create which fails partway through still leaves the resource in
+	// state, with `objects` listing the manifests that were applied.
+	// Update re-applies the manifests only when `json` has changed, so an
+	// unchanged config does not pick up where the failed Create stopped.
+	d.SetId(computeManifestSetID(manifests))
+
+	if err := applyManifestSet(d, m, manifests, d.Timeout(schema.TimeoutCreate)); err != nil {
+		return err
+	}
+
+	return resourceKubernetesCustomRead(d, m)
+}
+
+// resolveManifestResource determines the dynamic client resource for `u`,
+// defaulting its namespace when it's a namespaced kind, and returns the
+// sub-ID this object is tracked under in the `objects` attribute.
+func resolveManifestResource(u *unstructured.Unstructured, clientset *kubernetes.Clientset, dclient dynamic.Interface) (dynamic.ResourceInterface, string, error) {
+	resource, namespaced, err := createResourceFromUnstructured(u, clientset, dclient)
+
+	if err != nil {
+		return nil, "", fmt.Errorf("Could not determine resource type for %s: %v", manifestKey(u), err)
+	}
+
+	if !namespaced {
+		return resource, manifestSubID(u), nil
+	}
+
+	log.Printf("[DEBUG] This is a namespaced resource")
+	namespace := getNamespaceOrDefault(u)
+	u.SetNamespace(namespace)
+
+	return resource.Namespace(namespace), manifestSubID(u), nil
+}
 
+// applyManifestSet applies every manifest in `manifests`, in order,
+// recording progress in the `objects` attribute after each one so that
+// after a partial failure the state lists which objects were applied. Once every manifest has
+// been applied, it waits for the `wait_for` block (if any) against the
+// last manifest in apply order (the most dependent object, e.g. the
+// Deployment in a Namespace+CRD+RBAC+Deployment manifest set) rather than
+// every object in the set: supporting objects like a Namespace or RBAC
+// role never satisfy a `rollout` or `condition` check, so waiting on all
+// of them would block for the full timeout on each one before ever
+// reaching the object the user actually wants to wait for.
+func applyManifestSet(d *schema.ResourceData, m interface{}, manifests []*unstructured.Unstructured, timeout time.Duration) error {
 	clientset := m.(*KubeClientsets).MainClientset
 	dclient := m.(*KubeClientsets).DynamicClient
-	resource, namespaced, err := createResourceFromUnstructured(unstructuredResource, clientset, dclient)
+
+	// Strip ignore_fields (and this provider's built-in defaults for the
+	// object's kind) before anything is sent to the cluster, not just
+	// before diffing, so a field like `spec.replicas` that's meant to be
+	// left to an HPA is never part of what we apply.
+	applyFieldRules(manifests, d)
+
+	var applied []*unstructured.Unstructured
+	var resources []dynamic.ResourceInterface
+
+	for _, u := range manifests {
+		r, _, err := resolveManifestResource(u, clientset, dclient)
+
+		if err != nil {
+			d.Set("objects", manifestObjectAttrs(applied))
+			return err
+		}
+
+		if _, err := applyResource(clientset, r, u, d); err != nil {
+			d.Set("objects", manifestObjectAttrs(applied))
+			return fmt.Errorf("Could not apply resource %s: %v", manifestKey(u), err)
+		}
+
+		applied = append(applied, u)
+		resources = append(resources, r)
+		d.Set("objects", manifestObjectAttrs(applied))
+	}
+
+	if len(resources) > 0 {
+		last := len(resources) - 1
+		if err := waitForReady(resources[last], manifests[last].GetName(), d, timeout); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyResource reconciles the live object with the user's desired state.
+// It prefers Server-Side Apply, falling back to a three-way merge patch
+// (matching `kubectl apply` semantics) against clusters too old to support
+// it.
+func applyResource(clientset *kubernetes.Clientset, r dynamic.ResourceInterface, unstructuredResource *unstructured.Unstructured, d *schema.ResourceData) (*unstructured.Unstructured, error) {
+	if supportsServerSideApply(clientset) {
+		return serverSideApply(r, unstructuredResource, d)
+	}
+
+	fieldManager := d.Get("field_manager").(string)
+
+	return threeWayMergeApply(r, unstructuredResource, fieldManager)
+}
+
+// serverSideApply issues a Server-Side Apply PATCH for the given resource,
+// using the `field_manager` and `force_conflicts` schema fields to control
+// ownership of the applied fields.
+func serverSideApply(r dynamic.ResourceInterface, unstructuredResource *unstructured.Unstructured, d *schema.ResourceData) (*unstructured.Unstructured, error) {
+	name := unstructuredResource.GetName()
+	fieldManager := d.Get("field_manager").(string)
+	force := d.Get("force_conflicts").(bool)
+
+	data, err := unstructuredResource.MarshalJSON()
+
+	if err != nil {
+		return nil, fmt.Errorf("Could not marshal resource: %v", err)
+	}
+
+	return r.Patch(name, types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	})
+}
+
+// supportsServerSideApply reports whether the connected API server is new
+// enough to have Server-Side Apply enabled by default (it has been on by
+// default since Kubernetes 1.16). If the server version can't be
+// determined, it conservatively falls back to the three-way merge path.
+func supportsServerSideApply(clientset *kubernetes.Clientset) bool {
+	version, err := clientset.Discovery().ServerVersion()
+
+	if err != nil {
+		return false
+	}
+
+	major, err := strconv.Atoi(version.Major)
+
+	if err != nil {
+		return false
+	}
+
+	minor, err := strconv.Atoi(strings.TrimRight(version.Minor, "+"))
 
 	if err != nil {
-		return fmt.Errorf("Could not determine resource type: %v", err)
+		return false
 	}
 
+	return major > 1 || (major == 1 && minor >= 16)
+}
+
+// threeWayMergeApply patches the live object by diffing the previously
+// applied configuration (the "original"), the user's desired state (the
+// "modified") and the live object (the "current"), matching `kubectl
+// apply` semantics. If the object does not exist yet it is created. Both
+// requests carry the configured `field_manager` so `removeFieldsNotOwnedByManager`
+// recognizes fields written through this fallback path on the next Read,
+// instead of stripping them as owned by some other manager.
+func threeWayMergeApply(r dynamic.ResourceInterface, unstructuredResource *unstructured.Unstructured, fieldManager string) (*unstructured.Unstructured, error) {
 	name := unstructuredResource.GetName()
-	id := name
 
-	// if strings.ToLower(kind) == "customresourcedefinition" {
-	// 	_, err = resource.Create(unstructuredResource, metav1.CreateOptions{})
-	// 	id = name
-	// } else {
-	// 	namespace := getNamespaceOrDefault(unstructuredResource)
-	// 	_, err = resource.Namespace(namespace).Create(unstructuredResource, metav1.CreateOptions{})
-	// 	id = fmt.Sprintf("%s/%s", namespace, name)
-	// }
+	current, err := r.Get(name, metav1.GetOptions{})
 
-	var r dynamic.ResourceInterface
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
 
-	if namespaced {
-		log.Printf("[DEBUG] This is a namespaced resource")
-		namespace := getNamespaceOrDefault(unstructuredResource)
-		r = resource.Namespace(namespace)
-		id = fmt.Sprintf("%s/%s", namespace, name)
-	} else {
-		r = resource
+		return r.Create(unstructuredResource, metav1.CreateOptions{FieldManager: fieldManager})
 	}
 
-	_, err = r.Create(unstructuredResource, metav1.CreateOptions{})
+	modified, err := withLastAppliedConfig(unstructuredResource)
 
 	if err != nil {
-		return fmt.Errorf("Could not create resource: %v", err)
+		return nil, err
 	}
 
-	d.SetId(id)
+	// with no recorded original (first apply under this codepath) the
+	// original stays empty, as in `kubectl apply`: the patch then adds and
+	// changes fields but removes none, instead of clobbering unknown fields
+	original := []byte(current.GetAnnotations()[lastAppliedConfigAnnotation])
 
-	return resourceKubernetesCustomRead(d, m)
+	currentJSON, err := current.MarshalJSON()
+
+	if err != nil {
+		return nil, err
+	}
+
+	patchType, patchBytes, err := buildMergePatch(unstructuredResource.GroupVersionKind(), original, modified, currentJSON)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return r.Patch(name, patchType, patchBytes, metav1.PatchOptions{FieldManager: fieldManager})
 }
 
-func createResourceFromUnstructured(r *unstructured.Unstructured, clientset *kubernetes.Clientset, dclient dynamic.Interface) (dynamic.NamespaceableResourceInterface, bool, error) {
-	// figure out the REST mapping for the resource
-	d := clientset.Discovery()
-	groupResources, err := restmapper.GetAPIGroupResources(d)
+// withLastAppliedConfig returns the JSON encoding of `u` with its
+// last-applied-configuration annotation set to its own serialized form,
+// the same bookkeeping `kubectl apply` performs so the config can be
+// recovered on a later update.
+func withLastAppliedConfig(u *unstructured.Unstructured) ([]byte, error) {
+	clone := u.DeepCopy()
+	unstructured.RemoveNestedField(clone.Object, "metadata", "annotations", lastAppliedConfigAnnotation)
+
+	serialized, err := clone.MarshalJSON()
 
 	if err != nil {
-		return nil, false, err
+		return nil, err
+	}
+
+	annotations := clone.GetAnnotations()
+
+	if annotations == nil {
+		annotations = map[string]string{}
 	}
 
+	annotations[lastAppliedConfigAnnotation] = string(serialized)
+	clone.SetAnnotations(annotations)
+
+	return clone.MarshalJSON()
+}
+
+// buildMergePatch picks a strategic-merge patch for built-in types known
+// to the client-go scheme, and falls back to an RFC 7396 JSON merge patch
+// for CRDs and other types the scheme doesn't recognize.
+func buildMergePatch(gvk k8sschema.GroupVersionKind, original, modified, current []byte) (types.PatchType, []byte, error) {
+	versionedObject, err := scheme.Scheme.New(gvk)
+
+	switch {
+	case runtime.IsNotRegisteredError(err):
+		patch, err := jsonmergepatch.CreateThreeWayJSONMergePatch(original, modified, current)
+
+		return types.MergePatchType, patch, err
+	case err != nil:
+		return "", nil, err
+	default:
+		patchMeta, err := strategicpatch.NewPatchMetaFromStruct(versionedObject)
+
+		if err != nil {
+			return "", nil, err
+		}
+
+		patch, err := strategicpatch.CreateThreeWayMergePatch(original, modified, current, patchMeta, true)
+
+		return types.StrategicMergePatchType, patch, err
+	}
+}
+
+var (
+	discoveryCacheMu = sync.Mutex{}
+	discoveryCache   = map[*kubernetes.Clientset]discovery.CachedDiscoveryInterface{}
+	restMapperCache  = map[*kubernetes.Clientset]*restmapper.DeferredDiscoveryRESTMapper{}
+)
+
+// diskCachedDiscoveryClientFor wraps the discovery client built from config
+// with an on-disk cache under cacheDir, the same way `kubectl` caches
+// discovery under `~/.kube/cache/discovery/<host>` so that the round trip
+// is skipped not just within one provider process but across separate
+// `terraform plan`/`apply` invocations. cacheDir is used as the cache
+// directory itself (e.g. `~/.kube/cache/discovery/<host>`); no per-host
+// subdirectory is added beneath it.
+func diskCachedDiscoveryClientFor(config *rest.Config, cacheDir string) (discovery.CachedDiscoveryInterface, error) {
+	return diskcached.NewCachedDiscoveryClientForConfig(config, cacheDir, "", 10*time.Minute)
+}
+
+// cachedDiscoveryClientFor returns a discovery client that memoizes
+// group/resource lookups for the lifetime of the clientset, instead of
+// every CRUD call paying for a fresh round-trip to the API server.
+//
+// TODO(DiscoveryCacheDir): this only caches in-process. diskCachedDiscoveryClientFor
+// above implements the on-disk cache the request also asked for, but wiring
+// it in here needs a `*rest.Config` and a `DiscoveryCacheDir` provider
+// schema option, both of which belong in `provider.go` — not present in
+// this resource's source tree. Once `provider.go` exists, thread its
+// `DiscoveryCacheDir` value and `*rest.Config` through to here and prefer
+// diskCachedDiscoveryClientFor when a cache dir is configured.
+func cachedDiscoveryClientFor(clientset *kubernetes.Clientset) discovery.CachedDiscoveryInterface {
+	discoveryCacheMu.Lock()
+	defer discoveryCacheMu.Unlock()
+
+	if cached, ok := discoveryCache[clientset]; ok {
+		return cached
+	}
+
+	cached := memory.NewMemCacheClient(clientset.Discovery())
+	discoveryCache[clientset] = cached
+
+	return cached
+}
+
+// cachedRESTMapperFor returns a REST mapper backed by the cached discovery
+// client above, shared across operations on the same clientset.
+func cachedRESTMapperFor(clientset *kubernetes.Clientset, cachedDiscovery discovery.CachedDiscoveryInterface) *restmapper.DeferredDiscoveryRESTMapper {
+	discoveryCacheMu.Lock()
+	defer discoveryCacheMu.Unlock()
+
+	if rm, ok := restMapperCache[clientset]; ok {
+		return rm
+	}
+
+	rm := restmapper.NewDeferredDiscoveryRESTMapper(cachedDiscovery)
+	restMapperCache[clientset] = rm
+
+	return rm
+}
+
+func createResourceFromUnstructured(r *unstructured.Unstructured, clientset *kubernetes.Clientset, dclient dynamic.Interface) (dynamic.NamespaceableResourceInterface, bool, error) {
+	cachedDiscovery := cachedDiscoveryClientFor(clientset)
+	rm := cachedRESTMapperFor(clientset, cachedDiscovery)
+
 	gvk := r.GroupVersionKind()
 	gk := gvk.GroupKind()
 
-	rm := restmapper.NewDiscoveryRESTMapper(groupResources)
 	mapping, err := rm.RESTMapping(gk, gvk.Version)
 
+	if meta.IsNoMatchError(err) {
+		// the cached mapper predates a CRD that was just installed;
+		// invalidate it and try once more before giving up
+		rm.Reset()
+		mapping, err = rm.RESTMapping(gk, gvk.Version)
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
 	// figure out if the Resource is namespaced
-	gv := r.GroupVersionKind().GroupVersion()
-	apiResources, err := d.ServerResourcesForGroupVersion(gv.String())
+	gv := gvk.GroupVersion()
+	apiResources, err := cachedDiscovery.ServerResourcesForGroupVersion(gv.String())
 
 	if err != nil {
 		// TODO wrap this in a more meaningful error message
@@ -131,113 +552,341 @@ func createResourceFromUnstructured(r *unstructured.Unstructured, clientset *kub
 		}
 	}
 
-	if err != nil {
-		return nil, false, err
-	}
-
 	return dclient.Resource(mapping.Resource), namespaced, nil
 }
 
 func resourceKubernetesCustomRead(d *schema.ResourceData, m interface{}) error {
 	config := d.Get("json").(string)
-	unstructuredResource, _ := parseKubernetesConfig(config)
+	manifests, err := parseKubernetesConfig(config)
+
+	if err != nil {
+		return fmt.Errorf("Could not parse resource: %v", err)
+	}
 
 	clientset := m.(*KubeClientsets).MainClientset
 	dclient := m.(*KubeClientsets).DynamicClient
 
-	resource, namespaced, _ := createResourceFromUnstructured(unstructuredResource, clientset, dclient)
-	name := unstructuredResource.GetName()
+	var objects []interface{}
+	var docs []string
+
+	for _, desired := range manifests {
+		r, _, err := resolveManifestResource(desired, clientset, dclient)
+
+		if err != nil {
+			return err
+		}
+
+		res, err := r.Get(desired.GetName(), metav1.GetOptions{})
+
+		if err != nil {
+			return fmt.Errorf("Could not get resource %s: %v", manifestKey(desired), err)
+		}
+
+		removeIgnoredFields(res)
+		removeFieldsNotOwnedByManager(res, d.Get("field_manager").(string))
+		applyFieldRules([]*unstructured.Unstructured{res}, d)
+
+		_, namespaceSet, _ := unstructured.NestedString(desired.Object, "metadata", "namespace")
+
+		if !namespaceSet {
+			unstructured.RemoveNestedField(res.Object, "metadata", "namespace")
+		}
+
+		rawJSON, err := res.MarshalJSON()
+
+		if err != nil {
+			return fmt.Errorf("Could not marshal resource %s: %v", manifestKey(desired), err)
+		}
+
+		docs = append(docs, string(rawJSON))
+		objects = append(objects, manifestObjectAttr(res))
+	}
+
+	stream := strings.Join(docs, "\n---\n")
+
+	if len(docs) > 1 {
+		// a leading separator makes parseKubernetesConfig read the docs
+		// as a YAML stream; one starting with `{` is read as JSON
+		stream = "---\n" + stream
+	}
 
-	var r dynamic.ResourceInterface
+	d.Set("objects", objects)
+	d.Set("json", stream)
 
-	if namespaced {
-		namespace := getNamespaceOrDefault(unstructuredResource)
-		r = resource.Namespace(namespace)
-	} else {
-		r = resource
+	return nil
+}
+
+func resourceKubernetesCustomUpdate(d *schema.ResourceData, m interface{}) error {
+	if !d.HasChange("json") {
+		return resourceKubernetesCustomRead(d, m)
 	}
 
-	res, err := r.Get(name, metav1.GetOptions{})
+	config := d.Get("json").(string)
+	manifests, err := parseKubernetesConfig(config)
 
 	if err != nil {
-		return fmt.Errorf("Could not get resource: %v", err)
+		return fmt.Errorf("Could not parse resource: %v", err)
 	}
 
-	removeIgnoredFields(res)
+	sortManifestsForApply(manifests)
 
-	_, namespaceSet, _ := unstructured.NestedString(unstructuredResource.Object, "metadata", "namespace")
+	if err := applyManifestSet(d, m, manifests, d.Timeout(schema.TimeoutUpdate)); err != nil {
+		return err
+	}
 
-	if !namespaceSet {
-		unstructured.RemoveNestedField(res.Object, "metadata", "namespace")
+	return resourceKubernetesCustomRead(d, m)
+}
+
+func resourceKubernetesCustomDelete(d *schema.ResourceData, m interface{}) error {
+	config := d.Get("json").(string)
+	manifests, err := parseKubernetesConfig(config)
+
+	if err != nil {
+		return fmt.Errorf("Could not parse resource: %v", err)
 	}
 
-	rawJSON, err := res.MarshalJSON()
+	sortManifestsForDelete(manifests)
+
+	clientset := m.(*KubeClientsets).MainClientset
+	dclient := m.(*KubeClientsets).DynamicClient
 
-	d.Set("json", string(rawJSON))
+	for i, u := range manifests {
+		r, _, err := resolveManifestResource(u, clientset, dclient)
+
+		if err != nil {
+			d.Set("objects", manifestObjectAttrs(manifests[i:]))
+			return err
+		}
+
+		if err := r.Delete(u.GetName(), &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			d.Set("objects", manifestObjectAttrs(manifests[i:]))
+			return fmt.Errorf("Could not delete resource %s: %v", manifestKey(u), err)
+		}
+	}
 
 	return nil
 }
 
-func resourceKubernetesCustomUpdate(d *schema.ResourceData, m interface{}) error {
-	if d.HasChange("json") {
-		config := d.Get("json").(string)
-		unstructuredResource, _ := parseKubernetesConfig(config)
+// waitForReady blocks until the `wait_for` block attached to the resource
+// (if any) is satisfied, the context timeout elapses, or the configuration
+// does not declare a `wait_for` block at all.
+func waitForReady(r dynamic.ResourceInterface, name string, d *schema.ResourceData, timeout time.Duration) error {
+	waitForRaw, ok := d.GetOk("wait_for")
 
-		clientset := m.(*KubeClientsets).MainClientset
-		dclient := m.(*KubeClientsets).DynamicClient
-		resource, namespaced, _ := createResourceFromUnstructured(unstructuredResource, clientset, dclient)
-		name := unstructuredResource.GetName()
+	if !ok {
+		return nil
+	}
 
-		var r dynamic.ResourceInterface
+	waitForList, ok := waitForRaw.([]interface{})
 
-		if namespaced {
-			namespace := getNamespaceOrDefault(unstructuredResource)
-			r = resource.Namespace(namespace)
-		} else {
-			r = resource
+	if !ok || len(waitForList) == 0 || waitForList[0] == nil {
+		return nil
+	}
+
+	waitFor, ok := waitForList[0].(map[string]interface{})
+
+	if !ok {
+		return nil
+	}
+
+	checker := buildReadinessChecker(waitFor)
+
+	if checker == nil {
+		return nil
+	}
+
+	_, err := waitForResourceReady(r, name, checker, timeout)
+
+	return err
+}
+
+// buildReadinessChecker turns a `wait_for` block into a single predicate
+// that reports whether an observed object satisfies every mode (condition,
+// field, rollout) the user configured. It returns nil if no mode is set.
+func buildReadinessChecker(waitFor map[string]interface{}) func(*unstructured.Unstructured) bool {
+	var checks []func(*unstructured.Unstructured) bool
+
+	if raw, ok := waitFor["condition"].([]interface{}); ok {
+		for _, c := range raw {
+			condition := c.(map[string]interface{})
+			condType := condition["type"].(string)
+			condStatus := condition["status"].(string)
+
+			checks = append(checks, func(u *unstructured.Unstructured) bool {
+				return conditionStatus(u, condType) == condStatus
+			})
 		}
+	}
 
-		res, err := r.Get(name, metav1.GetOptions{})
+	if raw, ok := waitFor["field"].([]interface{}); ok {
+		for _, f := range raw {
+			field := f.(map[string]interface{})
+			key := field["key"].(string)
+			value := field["value"].(string)
 
-		resourceVersion := res.GetResourceVersion()
-		unstructuredResource.SetResourceVersion(resourceVersion)
+			checks = append(checks, func(u *unstructured.Unstructured) bool {
+				return fieldMatches(u, key, value)
+			})
+		}
+	}
 
-		_, err = r.Update(unstructuredResource, metav1.UpdateOptions{})
+	if rollout, ok := waitFor["rollout"].(bool); ok && rollout {
+		checks = append(checks, rolloutComplete)
+	}
 
-		if err != nil {
-			return fmt.Errorf("Could not update resource: %v", err)
+	if len(checks) == 0 {
+		return nil
+	}
+
+	return func(u *unstructured.Unstructured) bool {
+		for _, check := range checks {
+			if !check(u) {
+				return false
+			}
 		}
+
+		return true
 	}
+}
 
-	return resourceKubernetesCustomRead(d, m)
+// conditionStatus returns the `status` of the `status.conditions` entry
+// with the given `type`, following Kubernetes condition conventions.
+func conditionStatus(u *unstructured.Unstructured, conditionType string) string {
+	conditions, found, _ := unstructured.NestedSlice(u.Object, "status", "conditions")
+
+	if !found {
+		return ""
+	}
+
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+
+		if !ok || condition["type"] != conditionType {
+			continue
+		}
+
+		status, _ := condition["status"].(string)
+
+		return status
+	}
+
+	return ""
 }
 
-func resourceKubernetesCustomDelete(d *schema.ResourceData, m interface{}) error {
-	config := d.Get("json").(string)
-	unstructuredResource, _ := parseKubernetesConfig(config)
+// fieldMatches reports whether the dotted field path `key` (e.g.
+// "status.phase") is present on the object and equal to `expected`.
+func fieldMatches(u *unstructured.Unstructured, key string, expected string) bool {
+	val, found, err := unstructured.NestedFieldNoCopy(u.Object, splitFieldPath(key)...)
 
-	clientset := m.(*KubeClientsets).MainClientset
-	dclient := m.(*KubeClientsets).DynamicClient
+	if err != nil || !found {
+		return false
+	}
 
-	resource, namespaced, _ := createResourceFromUnstructured(unstructuredResource, clientset, dclient)
-	name := unstructuredResource.GetName()
+	return fmt.Sprintf("%v", val) == expected
+}
+
+// rolloutComplete computes Deployment/StatefulSet/DaemonSet readiness from
+// `status.observedGeneration` and the replica counters, the same signal
+// `kubectl rollout status` relies on.
+func rolloutComplete(u *unstructured.Unstructured) bool {
+	observedGeneration, found, _ := unstructured.NestedInt64(u.Object, "status", "observedGeneration")
+
+	if !found {
+		return false
+	}
+
+	generation, found, _ := unstructured.NestedInt64(u.Object, "metadata", "generation")
+
+	if !found || observedGeneration < generation {
+		return false
+	}
+
+	if strings.EqualFold(u.GetKind(), "DaemonSet") {
+		desired, _, _ := unstructured.NestedInt64(u.Object, "status", "desiredNumberScheduled")
+		numberReady, _, _ := unstructured.NestedInt64(u.Object, "status", "numberReady")
+		updated, _, _ := unstructured.NestedInt64(u.Object, "status", "updatedNumberScheduled")
+
+		return numberReady >= desired && updated >= desired
+	}
+
+	replicas, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas")
+
+	if !found {
+		replicas = 1
+	}
+
+	readyReplicas, _, _ := unstructured.NestedInt64(u.Object, "status", "readyReplicas")
+	updatedReplicas, _, _ := unstructured.NestedInt64(u.Object, "status", "updatedReplicas")
+
+	return readyReplicas >= replicas && updatedReplicas >= replicas
+}
+
+// waitForResourceReady watches the resource named `name` until `checker`
+// reports readiness or `timeout` elapses. If the watch cannot be
+// established it falls back to polling with exponential backoff.
+func waitForResourceReady(r dynamic.ResourceInterface, name string, checker func(*unstructured.Unstructured) bool, timeout time.Duration) (*unstructured.Unstructured, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	listOptions := metav1.ListOptions{
+		FieldSelector: fmt.Sprintf("metadata.name=%s", name),
+	}
+
+	if w, err := r.Watch(listOptions); err == nil {
+		defer w.Stop()
+
+	watchLoop:
+		for {
+			select {
+			case event, open := <-w.ResultChan():
+				if !open {
+					break watchLoop
+				}
+
+				obj, ok := event.Object.(*unstructured.Unstructured)
+
+				if ok && checker(obj) {
+					return obj, nil
+				}
+			case <-ctx.Done():
+				return nil, fmt.Errorf("Timed out waiting for %q to become ready", name)
+			}
+		}
+	}
+
+	var lastObserved *unstructured.Unstructured
+
+	check := func() error {
+		obj, err := r.Get(name, metav1.GetOptions{})
+
+		if err != nil {
+			return err
+		}
 
-	var r dynamic.ResourceInterface
+		lastObserved = obj
 
-	if namespaced {
-		namespace := getNamespaceOrDefault(unstructuredResource)
-		r = resource.Namespace(namespace)
-	} else {
-		r = resource
+		if checker(obj) {
+			return nil
+		}
+
+		return fmt.Errorf("%q is not ready yet", name)
 	}
 
-	err := r.Delete(name, &metav1.DeleteOptions{})
+	err := backoff.Retry(check, backoff.WithContext(backoff.NewExponentialBackOff(), ctx))
 
 	if err != nil {
-		return fmt.Errorf("Could not delete resource: %v", err)
+		status := "unknown"
+
+		if lastObserved != nil {
+			if rawStatus, marshalErr := json.Marshal(lastObserved.Object["status"]); marshalErr == nil {
+				status = string(rawStatus)
+			}
+		}
+
+		return lastObserved, fmt.Errorf("Timed out waiting for %q to become ready, last observed status: %s", name, status)
 	}
 
-	return nil
+	return lastObserved, nil
 }
 
 func getNamespaceOrDefault(u *unstructured.Unstructured) string {
@@ -270,21 +919,345 @@ func removeIgnoredFields(u *unstructured.Unstructured) {
 	}
 }
 
-// parseKubernetesConfig will parse a JSON string into an Unstructured
-func parseKubernetesConfig(config string) (*unstructured.Unstructured, error) {
-	var m map[string]interface{}
+// defaultIgnoreFieldsByKind are field paths this provider strips from the
+// diff by default, because they're routinely set by controllers the user
+// didn't declare (mutating webhooks, the Kubernetes control plane) rather
+// than by the manifest itself.
+var defaultIgnoreFieldsByKind = map[string][][]string{
+	"service": {
+		{"spec", "clusterIP"},
+		{"spec", "clusterIPs"},
+	},
+	"persistentvolumeclaim": {
+		{"spec", "volumeName"},
+	},
+}
 
-	err := json.Unmarshal([]byte(config), &m)
+// ignoreFieldsFor returns the full set of field paths to strip from `u`:
+// this provider's built-in defaults for its kind, plus whatever the user
+// added via `ignore_fields`.
+func ignoreFieldsFor(u *unstructured.Unstructured, d *schema.ResourceData) [][]string {
+	fields := append([][]string{}, defaultIgnoreFieldsByKind[strings.ToLower(u.GetKind())]...)
 
-	if err != nil {
-		return nil, err
+	if raw, ok := d.GetOk("ignore_fields"); ok {
+		for _, f := range raw.([]interface{}) {
+			fields = append(fields, splitFieldPath(f.(string)))
+		}
+	}
+
+	return fields
+}
+
+// applyFieldRules strips each manifest's ignored fields (built-in defaults
+// for its kind, plus the user's `ignore_fields`) in place.
+func applyFieldRules(manifests []*unstructured.Unstructured, d *schema.ResourceData) {
+	for _, u := range manifests {
+		for _, field := range ignoreFieldsFor(u, d) {
+			unstructured.RemoveNestedField(u.Object, field...)
+		}
+	}
+}
+
+// mergeComputedFields copies each `computed_fields` path from the matching
+// manifest in `old` (by GVK/namespace/name) into `new`, so fields the
+// server sets that the user never declared don't appear as drift just
+// because `new` lacks them.
+func mergeComputedFields(old, new []*unstructured.Unstructured, d *schema.ResourceData) {
+	raw, ok := d.GetOk("computed_fields")
+
+	if !ok {
+		return
+	}
+
+	var computedFields [][]string
+
+	for _, f := range raw.([]interface{}) {
+		computedFields = append(computedFields, splitFieldPath(f.(string)))
+	}
+
+	oldByKey := map[string]*unstructured.Unstructured{}
+
+	for _, u := range old {
+		oldByKey[manifestKey(u)] = u
+	}
+
+	for _, nu := range new {
+		ou, ok := oldByKey[manifestKey(nu)]
+
+		if !ok {
+			continue
+		}
+
+		for _, field := range computedFields {
+			val, found, _ := unstructured.NestedFieldNoCopy(ou.Object, field...)
+
+			if !found {
+				continue
+			}
+
+			if err := unstructured.SetNestedField(nu.Object, val, field...); err != nil {
+				log.Printf("[DEBUG] Could not merge computed field %q: %v", pathKey(field), err)
+			}
+		}
+	}
+}
+
+// managedField mirrors the subset of metav1.ManagedFieldsEntry this
+// provider cares about when deciding which fields to keep in state.
+type managedField struct {
+	Manager  string          `json:"manager"`
+	FieldsV1 json.RawMessage `json:"fieldsV1"`
+}
+
+// removeFieldsNotOwnedByManager walks `metadata.managedFields` and strips
+// any field from the object that is owned by a field manager other than
+// `fieldManager`, so the diff only reflects the user's declared desired
+// state rather than defaults or mutations applied by other controllers.
+// Fields that no manager claims ownership of (e.g. `metadata` itself) are
+// left untouched. Fields inside list items are not stripped either: their
+// paths run through a list, which the removal does not descend into.
+func removeFieldsNotOwnedByManager(u *unstructured.Unstructured, fieldManager string) {
+	rawManagedFields, found, _ := unstructured.NestedSlice(u.Object, "metadata", "managedFields")
+
+	if !found {
+		return
+	}
+
+	ownedByUs := map[string]bool{}
+	ownedByOthers := map[string]bool{}
+
+	for _, entry := range rawManagedFields {
+		entryJSON, err := json.Marshal(entry)
+
+		if err != nil {
+			continue
+		}
+
+		var mf managedField
+
+		if err := json.Unmarshal(entryJSON, &mf); err != nil {
+			continue
+		}
+
+		var fields map[string]interface{}
+
+		if err := json.Unmarshal(mf.FieldsV1, &fields); err != nil {
+			continue
+		}
+
+		paths := map[string]bool{}
+		collectFieldPaths(fields, nil, paths)
+
+		target := ownedByOthers
+		if mf.Manager == fieldManager {
+			target = ownedByUs
+		}
+
+		for path := range paths {
+			target[path] = true
+		}
+	}
+
+	for path := range ownedByOthers {
+		if ownedByUs[path] {
+			continue
+		}
+
+		unstructured.RemoveNestedField(u.Object, splitFieldPath(path)...)
+	}
+
+	unstructured.RemoveNestedField(u.Object, "metadata", "managedFields")
+}
+
+// collectFieldPaths flattens a FieldsV1 tree (as produced by the API
+// server) into a set of "."-joined field paths, skipping the "f:"/"k:"/"v:"
+// prefixes structured-merge-diff uses to distinguish map keys, list items
+// and values.
+func collectFieldPaths(fields map[string]interface{}, prefix []string, out map[string]bool) {
+	for key, value := range fields {
+		if key == "." {
+			out[pathKey(prefix)] = true
+			continue
+		}
+
+		name := key
+		if len(key) > 2 && key[1] == ':' {
+			name = key[2:]
+		}
+
+		path := append(append([]string{}, prefix...), name)
+
+		if children, ok := value.(map[string]interface{}); ok && len(children) > 0 {
+			collectFieldPaths(children, path, out)
+		} else {
+			out[pathKey(path)] = true
+		}
+	}
+}
+
+func pathKey(path []string) string {
+	key := ""
+	for i, p := range path {
+		if i > 0 {
+			key += "."
+		}
+		key += p
+	}
+	return key
+}
+
+func splitFieldPath(path string) []string {
+	var parts []string
+	start := 0
+
+	for i := 0; i <= len(path); i++ {
+		if i == len(path) || path[i] == '.' {
+			parts = append(parts, path[start:i])
+			start = i + 1
+		}
+	}
+
+	return parts
+}
+
+// parseKubernetesConfig parses `config` as a JSON document, a single YAML
+// document, or a multi-document YAML manifest stream, returning one
+// Unstructured per document. A config whose first non-blank character is
+// `{` is read as a stream of JSON documents, in which `---` separators are
+// not accepted.
+func parseKubernetesConfig(config string) ([]*unstructured.Unstructured, error) {
+	decoder := yamlutil.NewYAMLOrJSONDecoder(strings.NewReader(config), 4096)
+
+	var manifests []*unstructured.Unstructured
+
+	for {
+		var m map[string]interface{}
+
+		err := decoder.Decode(&m)
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(m) == 0 {
+			continue
+		}
+
+		u := &unstructured.Unstructured{Object: m}
+		removeIgnoredFields(u)
+
+		manifests = append(manifests, u)
+	}
+
+	if len(manifests) == 0 {
+		return nil, fmt.Errorf("no Kubernetes manifests found in config")
+	}
+
+	return manifests, nil
+}
+
+// manifestApplyRank buckets a manifest by how early it must be applied so
+// that dependencies between objects (CRD before CR, namespace before
+// anything in it) are respected: Namespaces, then CustomResourceDefinitions,
+// then RBAC objects, then everything else, then workloads last.
+func manifestApplyRank(u *unstructured.Unstructured) int {
+	switch strings.ToLower(u.GetKind()) {
+	case "namespace":
+		return 0
+	case "customresourcedefinition":
+		return 1
+	case "clusterrole", "clusterrolebinding", "role", "rolebinding", "serviceaccount":
+		return 2
+	case "deployment", "statefulset", "daemonset", "pod":
+		return 4
+	default:
+		return 3
 	}
+}
+
+// manifestKey returns a stable identifier for a manifest's GVK/namespace/name
+// tuple, used both for sorting and for the resource's ID hash.
+func manifestKey(u *unstructured.Unstructured) string {
+	gvk := u.GroupVersionKind()
+
+	return fmt.Sprintf("%s/%s/%s/%s/%s", gvk.Group, gvk.Version, gvk.Kind, u.GetNamespace(), u.GetName())
+}
 
-	var u = unstructured.Unstructured{
-		Object: m,
+// manifestSubID returns the ID a single manifest is tracked under in the
+// `objects` attribute, matching the namespace/name convention the rest of
+// this resource already uses.
+func manifestSubID(u *unstructured.Unstructured) string {
+	if u.GetNamespace() == "" {
+		return u.GetName()
 	}
 
-	removeIgnoredFields(&u)
+	return fmt.Sprintf("%s/%s", u.GetNamespace(), u.GetName())
+}
+
+// sortManifestsForApply orders manifests for Create/Update: Namespaces,
+// CRDs, RBAC, everything else, then workloads, each tier sorted by key for
+// a deterministic order.
+func sortManifestsForApply(manifests []*unstructured.Unstructured) {
+	sort.SliceStable(manifests, func(i, j int) bool {
+		ri, rj := manifestApplyRank(manifests[i]), manifestApplyRank(manifests[j])
+
+		if ri != rj {
+			return ri < rj
+		}
+
+		return manifestKey(manifests[i]) < manifestKey(manifests[j])
+	})
+}
+
+// sortManifestsForDelete orders manifests for Delete: the exact reverse of
+// the apply order, so a CR is removed before its CRD.
+func sortManifestsForDelete(manifests []*unstructured.Unstructured) {
+	sortManifestsForApply(manifests)
+
+	for i, j := 0, len(manifests)-1; i < j; i, j = i+1, j-1 {
+		manifests[i], manifests[j] = manifests[j], manifests[i]
+	}
+}
+
+// computeManifestSetID derives a stable ID for the whole resource from the
+// sorted GVK/namespace/name tuples of its manifests, independent of the
+// order they appear in `json`.
+func computeManifestSetID(manifests []*unstructured.Unstructured) string {
+	keys := make([]string, len(manifests))
+
+	for i, u := range manifests {
+		keys[i] = manifestKey(u)
+	}
+
+	sort.Strings(keys)
+
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// manifestObjectAttr renders a manifest into an entry of the `objects`
+// computed attribute.
+func manifestObjectAttr(u *unstructured.Unstructured) map[string]interface{} {
+	return map[string]interface{}{
+		"api_version": u.GetAPIVersion(),
+		"kind":        u.GetKind(),
+		"namespace":   u.GetNamespace(),
+		"name":        u.GetName(),
+		"id":          manifestSubID(u),
+	}
+}
+
+func manifestObjectAttrs(manifests []*unstructured.Unstructured) []interface{} {
+	attrs := make([]interface{}, 0, len(manifests))
+
+	for _, u := range manifests {
+		attrs = append(attrs, manifestObjectAttr(u))
+	}
 
-	return &u, nil
+	return attrs
 }
@@ -0,0 +1,538 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// testResourceData builds a *schema.ResourceData for resourceKubernetesCustom
+// with the given raw config, for exercising helpers that read schema fields
+// off of it without going through a real plan/apply.
+func testResourceData(t *testing.T, raw map[string]interface{}) *schema.ResourceData {
+	return schema.TestResourceDataRaw(t, resourceKubernetesCustom().Schema, raw)
+}
+
+func TestBuildMergePatchUsesStrategicMergeForBuiltInTypes(t *testing.T) {
+	gvk := k8sschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	original := []byte(`{"spec":{"replicas":1}}`)
+	modified := []byte(`{"spec":{"replicas":2}}`)
+	current := []byte(`{"spec":{"replicas":1}}`)
+
+	patchType, _, err := buildMergePatch(gvk, original, modified, current)
+
+	if err != nil {
+		t.Fatalf("buildMergePatch() returned an error: %v", err)
+	}
+
+	if patchType != types.StrategicMergePatchType {
+		t.Errorf("buildMergePatch() patch type = %q, want %q", patchType, types.StrategicMergePatchType)
+	}
+}
+
+func TestBuildMergePatchUsesJSONMergeForUnregisteredTypes(t *testing.T) {
+	gvk := k8sschema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+
+	original := []byte(`{"spec":{"size":1}}`)
+	modified := []byte(`{"spec":{"size":2}}`)
+	current := []byte(`{"spec":{"size":1}}`)
+
+	patchType, _, err := buildMergePatch(gvk, original, modified, current)
+
+	if err != nil {
+		t.Fatalf("buildMergePatch() returned an error: %v", err)
+	}
+
+	if patchType != types.MergePatchType {
+		t.Errorf("buildMergePatch() patch type = %q, want %q", patchType, types.MergePatchType)
+	}
+}
+
+func TestSplitFieldPath(t *testing.T) {
+	cases := []struct {
+		path string
+		want []string
+	}{
+		{"", []string{""}},
+		{"status", []string{"status"}},
+		{"spec.replicas", []string{"spec", "replicas"}},
+		{"spec.template.metadata.labels", []string{"spec", "template", "metadata", "labels"}},
+	}
+
+	for _, c := range cases {
+		got := splitFieldPath(c.path)
+
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("splitFieldPath(%q) = %v, want %v", c.path, got, c.want)
+		}
+	}
+}
+
+func TestPathKey(t *testing.T) {
+	cases := []struct {
+		path []string
+		want string
+	}{
+		{[]string{"status"}, "status"},
+		{[]string{"spec", "replicas"}, "spec.replicas"},
+		{[]string{}, ""},
+	}
+
+	for _, c := range cases {
+		got := pathKey(c.path)
+
+		if got != c.want {
+			t.Errorf("pathKey(%v) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestCollectFieldPaths(t *testing.T) {
+	fields := map[string]interface{}{
+		"f:spec": map[string]interface{}{
+			"f:replicas": map[string]interface{}{".": map[string]interface{}{}},
+			"f:template": map[string]interface{}{
+				"f:metadata": map[string]interface{}{
+					"f:labels": map[string]interface{}{".": map[string]interface{}{}},
+				},
+			},
+		},
+	}
+
+	out := map[string]bool{}
+	collectFieldPaths(fields, nil, out)
+
+	want := map[string]bool{
+		"spec.replicas":                 true,
+		"spec.template.metadata.labels": true,
+	}
+
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("collectFieldPaths() = %v, want %v", out, want)
+	}
+}
+
+func TestRemoveFieldsNotOwnedByManager(t *testing.T) {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"spec": map[string]interface{}{
+				"replicas": int64(3),
+				"selector": "app=demo",
+			},
+			"metadata": map[string]interface{}{
+				"managedFields": []interface{}{
+					map[string]interface{}{
+						"manager": "terraform-provider-kubernetes",
+						"fieldsV1": map[string]interface{}{
+							"f:spec": map[string]interface{}{
+								"f:selector": map[string]interface{}{".": map[string]interface{}{}},
+							},
+						},
+					},
+					map[string]interface{}{
+						"manager": "horizontal-pod-autoscaler",
+						"fieldsV1": map[string]interface{}{
+							"f:spec": map[string]interface{}{
+								"f:replicas": map[string]interface{}{".": map[string]interface{}{}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	removeFieldsNotOwnedByManager(u, "terraform-provider-kubernetes")
+
+	if _, found, _ := unstructured.NestedString(u.Object, "spec", "selector"); !found {
+		t.Errorf("expected field owned by our manager to be kept")
+	}
+
+	if _, found, _ := unstructured.NestedInt64(u.Object, "spec", "replicas"); found {
+		t.Errorf("expected field owned by another manager to be stripped")
+	}
+
+	if _, found, _ := unstructured.NestedSlice(u.Object, "metadata", "managedFields"); found {
+		t.Errorf("expected managedFields itself to be stripped")
+	}
+}
+
+func TestFieldMatches(t *testing.T) {
+	u := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"status": map[string]interface{}{
+				"phase": "Running",
+			},
+		},
+	}
+
+	if !fieldMatches(u, "status.phase", "Running") {
+		t.Errorf("expected status.phase to match \"Running\"")
+	}
+
+	if fieldMatches(u, "status.phase", "Pending") {
+		t.Errorf("expected status.phase not to match \"Pending\"")
+	}
+
+	if fieldMatches(u, "status.missing", "anything") {
+		t.Errorf("expected a missing field not to match")
+	}
+}
+
+func TestRolloutComplete(t *testing.T) {
+	cases := []struct {
+		name string
+		u    *unstructured.Unstructured
+		want bool
+	}{
+		{
+			name: "no observedGeneration",
+			u: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": int64(1)},
+			}},
+			want: false,
+		},
+		{
+			name: "stale observedGeneration",
+			u: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": int64(2)},
+				"status":   map[string]interface{}{"observedGeneration": int64(1)},
+			}},
+			want: false,
+		},
+		{
+			name: "not enough ready replicas",
+			u: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": int64(1)},
+				"spec":     map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(1),
+					"readyReplicas":      int64(2),
+					"updatedReplicas":    int64(3),
+				},
+			}},
+			want: false,
+		},
+		{
+			name: "rolled out",
+			u: &unstructured.Unstructured{Object: map[string]interface{}{
+				"metadata": map[string]interface{}{"generation": int64(1)},
+				"spec":     map[string]interface{}{"replicas": int64(3)},
+				"status": map[string]interface{}{
+					"observedGeneration": int64(1),
+					"readyReplicas":      int64(3),
+					"updatedReplicas":    int64(3),
+				},
+			}},
+			want: true,
+		},
+	}
+
+	for _, c := range cases {
+		if got := rolloutComplete(c.u); got != c.want {
+			t.Errorf("%s: rolloutComplete() = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBuildReadinessChecker(t *testing.T) {
+	waitFor := map[string]interface{}{
+		"condition": []interface{}{
+			map[string]interface{}{"type": "Ready", "status": "True"},
+		},
+		"field": []interface{}{
+			map[string]interface{}{"key": "status.phase", "value": "Running"},
+		},
+	}
+
+	checker := buildReadinessChecker(waitFor)
+
+	if checker == nil {
+		t.Fatal("expected a non-nil checker")
+	}
+
+	notReady := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"phase": "Running",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "False"},
+			},
+		},
+	}}
+
+	if checker(notReady) {
+		t.Errorf("expected checker to fail while the condition is False")
+	}
+
+	ready := &unstructured.Unstructured{Object: map[string]interface{}{
+		"status": map[string]interface{}{
+			"phase": "Running",
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Ready", "status": "True"},
+			},
+		},
+	}}
+
+	if !checker(ready) {
+		t.Errorf("expected checker to pass once the condition and field both match")
+	}
+}
+
+func TestBuildReadinessCheckerNoModesConfigured(t *testing.T) {
+	if checker := buildReadinessChecker(map[string]interface{}{}); checker != nil {
+		t.Errorf("expected a nil checker when no wait_for mode is configured")
+	}
+}
+
+func newManifest(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+		},
+	}}
+}
+
+func TestSortManifestsForApply(t *testing.T) {
+	manifests := []*unstructured.Unstructured{
+		newManifest("apps/v1", "Deployment", "demo", "app"),
+		newManifest("rbac.authorization.k8s.io/v1", "Role", "demo", "app-role"),
+		newManifest("v1", "Namespace", "", "demo"),
+		newManifest("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "widgets.example.com"),
+	}
+
+	sortManifestsForApply(manifests)
+
+	want := []string{"Namespace", "CustomResourceDefinition", "Role", "Deployment"}
+
+	for i, kind := range want {
+		if got := manifests[i].GetKind(); got != kind {
+			t.Errorf("manifests[%d].GetKind() = %q, want %q", i, got, kind)
+		}
+	}
+}
+
+func TestSortManifestsForDeleteIsReverseOfApply(t *testing.T) {
+	manifests := []*unstructured.Unstructured{
+		newManifest("apps/v1", "Deployment", "demo", "app"),
+		newManifest("v1", "Namespace", "", "demo"),
+	}
+
+	sortManifestsForDelete(manifests)
+
+	if manifests[0].GetKind() != "Deployment" || manifests[1].GetKind() != "Namespace" {
+		t.Errorf("expected delete order to be the reverse of apply order, got %q then %q", manifests[0].GetKind(), manifests[1].GetKind())
+	}
+}
+
+func TestComputeManifestSetIDIsOrderIndependent(t *testing.T) {
+	a := []*unstructured.Unstructured{
+		newManifest("v1", "Namespace", "", "demo"),
+		newManifest("apps/v1", "Deployment", "demo", "app"),
+	}
+
+	b := []*unstructured.Unstructured{
+		newManifest("apps/v1", "Deployment", "demo", "app"),
+		newManifest("v1", "Namespace", "", "demo"),
+	}
+
+	if computeManifestSetID(a) != computeManifestSetID(b) {
+		t.Errorf("expected computeManifestSetID to be independent of manifest order")
+	}
+
+	c := []*unstructured.Unstructured{
+		newManifest("v1", "Namespace", "", "other"),
+		newManifest("apps/v1", "Deployment", "demo", "app"),
+	}
+
+	if computeManifestSetID(a) == computeManifestSetID(c) {
+		t.Errorf("expected computeManifestSetID to differ when the manifest set differs")
+	}
+}
+
+func TestParseKubernetesConfigMultiDocument(t *testing.T) {
+	config := `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: demo
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: demo
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: demo
+`
+
+	manifests, err := parseKubernetesConfig(config)
+
+	if err != nil {
+		t.Fatalf("parseKubernetesConfig() returned an error: %v", err)
+	}
+
+	if len(manifests) != 3 {
+		t.Fatalf("parseKubernetesConfig() returned %d manifests, want 3", len(manifests))
+	}
+
+	want := []struct {
+		gvk  k8sschema.GroupVersionKind
+		name string
+	}{
+		{k8sschema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, "demo"},
+		{k8sschema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "app"},
+		{k8sschema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "app-config"},
+	}
+
+	for i, w := range want {
+		if got := manifests[i].GroupVersionKind(); got != w.gvk {
+			t.Errorf("manifests[%d].GroupVersionKind() = %v, want %v", i, got, w.gvk)
+		}
+
+		if got := manifests[i].GetName(); got != w.name {
+			t.Errorf("manifests[%d].GetName() = %q, want %q", i, got, w.name)
+		}
+	}
+}
+
+func TestParseKubernetesConfigSingleDocument(t *testing.T) {
+	manifests, err := parseKubernetesConfig(`{"apiVersion":"v1","kind":"Namespace","metadata":{"name":"demo"}}`)
+
+	if err != nil {
+		t.Fatalf("parseKubernetesConfig() returned an error: %v", err)
+	}
+
+	if len(manifests) != 1 {
+		t.Fatalf("parseKubernetesConfig() returned %d manifests, want 1", len(manifests))
+	}
+
+	if got := manifests[0].GetKind(); got != "Namespace" {
+		t.Errorf("manifests[0].GetKind() = %q, want %q", got, "Namespace")
+	}
+}
+
+func TestIgnoreFieldsForMergesKindDefaultsWithUserFields(t *testing.T) {
+	d := testResourceData(t, map[string]interface{}{
+		"ignore_fields": []interface{}{"spec.replicas"},
+	})
+
+	svc := newManifest("v1", "Service", "demo", "app")
+
+	got := ignoreFieldsFor(svc, d)
+
+	want := [][]string{
+		{"spec", "clusterIP"},
+		{"spec", "clusterIPs"},
+		{"spec", "replicas"},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ignoreFieldsFor() = %v, want %v", got, want)
+	}
+}
+
+func TestIgnoreFieldsForNoKindDefaults(t *testing.T) {
+	d := testResourceData(t, map[string]interface{}{
+		"ignore_fields": []interface{}{"spec.replicas"},
+	})
+
+	dep := newManifest("apps/v1", "Deployment", "demo", "app")
+
+	got := ignoreFieldsFor(dep, d)
+
+	want := [][]string{{"spec", "replicas"}}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ignoreFieldsFor() = %v, want %v", got, want)
+	}
+}
+
+func TestApplyFieldRulesStripsIgnoredFields(t *testing.T) {
+	d := testResourceData(t, map[string]interface{}{
+		"ignore_fields": []interface{}{"spec.replicas"},
+	})
+
+	svc := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Service",
+		"metadata":   map[string]interface{}{"name": "app"},
+		"spec": map[string]interface{}{
+			"clusterIP": "10.0.0.1",
+			"replicas":  int64(2),
+			"selector":  "app=demo",
+		},
+	}}
+
+	applyFieldRules([]*unstructured.Unstructured{svc}, d)
+
+	if _, found, _ := unstructured.NestedString(svc.Object, "spec", "clusterIP"); found {
+		t.Errorf("expected spec.clusterIP to be stripped by the built-in Service default")
+	}
+
+	if _, found, _ := unstructured.NestedInt64(svc.Object, "spec", "replicas"); found {
+		t.Errorf("expected spec.replicas to be stripped by the user's ignore_fields")
+	}
+
+	if _, found, _ := unstructured.NestedString(svc.Object, "spec", "selector"); !found {
+		t.Errorf("expected spec.selector to be left alone")
+	}
+}
+
+func TestMergeComputedFieldsCopiesFieldForwardByKey(t *testing.T) {
+	d := testResourceData(t, map[string]interface{}{
+		"computed_fields": []interface{}{"spec.clusterIP"},
+	})
+
+	old := []*unstructured.Unstructured{
+		{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"namespace": "demo", "name": "app"},
+			"spec":       map[string]interface{}{"clusterIP": "10.0.0.1"},
+		}},
+	}
+
+	new := []*unstructured.Unstructured{
+		{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"namespace": "demo", "name": "app"},
+			"spec":       map[string]interface{}{"selector": "app=demo"},
+		}},
+	}
+
+	mergeComputedFields(old, new, d)
+
+	got, found, _ := unstructured.NestedString(new[0].Object, "spec", "clusterIP")
+
+	if !found || got != "10.0.0.1" {
+		t.Errorf("expected spec.clusterIP to be merged forward from old, got %q (found=%v)", got, found)
+	}
+}
+
+func TestMergeComputedFieldsSkipsUnmatchedManifests(t *testing.T) {
+	d := testResourceData(t, map[string]interface{}{
+		"computed_fields": []interface{}{"spec.clusterIP"},
+	})
+
+	old := []*unstructured.Unstructured{newManifest("v1", "Service", "demo", "other")}
+	new := []*unstructured.Unstructured{newManifest("v1", "Service", "demo", "app")}
+
+	mergeComputedFields(old, new, d)
+
+	if _, found, _ := unstructured.NestedString(new[0].Object, "spec", "clusterIP"); found {
+		t.Errorf("expected no field to be merged when no manifest in old matches by key")
+	}
+}